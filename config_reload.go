@@ -0,0 +1,169 @@
+// Copyright 2015 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	fsnotify "gopkg.in/fsnotify.v1"
+)
+
+var (
+	configLastReloadSuccessful = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "alertmanager_config_last_reload_successful",
+		Help: "Whether the last configuration reload attempt was successful.",
+	})
+	configLastReloadSuccessTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "alertmanager_config_last_reload_success_timestamp_seconds",
+		Help: "Timestamp of the last successful configuration reload.",
+	})
+	configLoadsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "alertmanager_config_loads_total",
+		Help: "Total number of attempted configuration reloads, partitioned by result.",
+	}, []string{"result"})
+)
+
+func init() {
+	prometheus.MustRegister(configLastReloadSuccessful)
+	prometheus.MustRegister(configLastReloadSuccessTimestamp)
+	prometheus.MustRegister(configLoadsTotal)
+}
+
+// instrumentedReload wraps reload so that every attempt, whether triggered by
+// SIGHUP or the config watcher, updates the reload metrics.
+func instrumentedReload(reload func() error, now func() float64) func() error {
+	return func() error {
+		err := reload()
+		if err != nil {
+			configLastReloadSuccessful.Set(0)
+			configLoadsTotal.WithLabelValues("failure").Inc()
+			return err
+		}
+		configLastReloadSuccessful.Set(1)
+		configLastReloadSuccessTimestamp.Set(now())
+		configLoadsTotal.WithLabelValues("success").Inc()
+		return nil
+	}
+}
+
+// configWatcher watches the main configuration file and the template files
+// resolved from it, re-triggering reload on changes. Editors like vim replace
+// a file on save via a rename-delete sequence, so watches on individual files
+// are re-armed after a Remove/Rename event instead of relying on a directory
+// watch picking the inode back up. Re-arming succeeds only if a file is back
+// at the path when the event is handled; otherwise the path is dropped from
+// the watched set, and the next setFiles call tries to watch it again.
+type configWatcher struct {
+	watcher *fsnotify.Watcher
+	reload  func() error
+
+	mtx   sync.Mutex
+	files map[string]struct{}
+}
+
+func newConfigWatcher(reload func() error) (*configWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	cw := &configWatcher{
+		watcher: w,
+		reload:  reload,
+		files:   map[string]struct{}{},
+	}
+	go cw.run()
+	return cw, nil
+}
+
+// setFiles replaces the set of watched files with files, adding watches for
+// newly seen paths and dropping watches for paths no longer in use.
+func (w *configWatcher) setFiles(files []string) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	next := make(map[string]struct{}, len(files))
+	for _, f := range files {
+		if _, ok := w.files[f]; ok {
+			next[f] = struct{}{}
+			continue
+		}
+		if err := w.watcher.Add(f); err != nil {
+			log.With("file", f).With("err", err).Errorln("Failed to watch file for config auto-reload")
+			continue
+		}
+		next[f] = struct{}{}
+	}
+	for f := range w.files {
+		if _, ok := next[f]; !ok {
+			w.watcher.Remove(f)
+		}
+	}
+	w.files = next
+}
+
+func (w *configWatcher) run() {
+	for {
+		select {
+		case ev, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			switch {
+			case ev.Op&(fsnotify.Write|fsnotify.Create) != 0:
+				log.With("file", ev.Name).Infoln("Config file changed, reloading")
+				w.reload()
+			case ev.Op&(fsnotify.Rename|fsnotify.Remove) != 0:
+				// Editors typically rename the old file out of the way and
+				// create a new one in its place; re-add the watch so we
+				// keep tracking the path rather than a stale inode.
+				w.mtx.Lock()
+				if err := w.watcher.Add(ev.Name); err == nil {
+					w.files[ev.Name] = struct{}{}
+				} else {
+					delete(w.files, ev.Name)
+				}
+				w.mtx.Unlock()
+				log.With("file", ev.Name).Infoln("Config file replaced, reloading")
+				w.reload()
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.With("err", err).Errorln("Error watching configuration files")
+		}
+	}
+}
+
+func (w *configWatcher) Close() error {
+	return w.watcher.Close()
+}
+
+// templateFiles resolves the concrete set of files backing the given
+// template globs, as template.FromGlobs would expand them.
+func templateFiles(globs []string) []string {
+	var files []string
+	for _, g := range globs {
+		matches, err := filepath.Glob(g)
+		if err != nil {
+			log.With("glob", g).With("err", err).Errorln("Invalid template glob")
+			continue
+		}
+		files = append(files, matches...)
+	}
+	return files
+}
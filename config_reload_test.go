@@ -0,0 +1,174 @@
+// Copyright 2015 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitForCount(t *testing.T, n *int32, want int32, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if atomic.LoadInt32(n) >= want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("reload count = %d after %v, want >= %d", atomic.LoadInt32(n), timeout, want)
+}
+
+func TestConfigWatcherReloadsOnWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	var reloads int32
+	w, err := newConfigWatcher(func() error {
+		atomic.AddInt32(&reloads, 1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("newConfigWatcher: %v", err)
+	}
+	defer w.Close()
+
+	w.setFiles([]string{path})
+
+	if err := os.WriteFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	waitForCount(t, &reloads, 1, 2*time.Second)
+}
+
+// TestConfigWatcherRearmsAfterRename exercises the rename-then-create
+// sequence editors like vim use for atomic saves: the watched path is
+// removed out from under the watch and a new file takes its place. The
+// watcher must re-arm on the same path rather than silently going quiet.
+func TestConfigWatcherRearmsAfterRename(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yml")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	var reloads int32
+	w, err := newConfigWatcher(func() error {
+		atomic.AddInt32(&reloads, 1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("newConfigWatcher: %v", err)
+	}
+	defer w.Close()
+
+	w.setFiles([]string{path})
+
+	tmp := filepath.Join(dir, "config.yml.swp")
+	if err := os.Rename(path, tmp); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	waitForCount(t, &reloads, 1, 2*time.Second)
+
+	// If the watch wasn't re-armed on path, this second write goes
+	// unnoticed and the test times out.
+	if err := os.WriteFile(path, []byte("v3"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	waitForCount(t, &reloads, 2, 2*time.Second)
+}
+
+func TestConfigWatcherSetFilesDropsStaleWatches(t *testing.T) {
+	dir := t.TempDir()
+	keep := filepath.Join(dir, "keep.tmpl")
+	drop := filepath.Join(dir, "drop.tmpl")
+	for _, p := range []string{keep, drop} {
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	var reloads int32
+	w, err := newConfigWatcher(func() error {
+		atomic.AddInt32(&reloads, 1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("newConfigWatcher: %v", err)
+	}
+	defer w.Close()
+
+	w.setFiles([]string{keep, drop})
+	w.setFiles([]string{keep})
+
+	if err := os.WriteFile(drop, []byte("y"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	// Give any spurious event a moment to arrive before asserting it didn't.
+	time.Sleep(200 * time.Millisecond)
+	if got := atomic.LoadInt32(&reloads); got != 0 {
+		t.Fatalf("reload count = %d after writing a dropped watch's file, want 0", got)
+	}
+}
+
+// TestConfigWatcherSetFilesRetriesFailedAdd covers a path that does not
+// exist yet (e.g. a template glob match that hasn't been created at the
+// moment of a reload): setFiles must not mark it watched until watcher.Add
+// actually succeeds, or every later call sees it as already-watched and
+// never retries, permanently breaking auto-reload for that path.
+func TestConfigWatcherSetFilesRetriesFailedAdd(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "not-yet-created.tmpl")
+
+	var reloads int32
+	w, err := newConfigWatcher(func() error {
+		atomic.AddInt32(&reloads, 1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("newConfigWatcher: %v", err)
+	}
+	defer w.Close()
+
+	// path doesn't exist yet, so this Add fails and must not be recorded.
+	w.setFiles([]string{path})
+	if _, ok := w.files[path]; ok {
+		t.Fatalf("setFiles recorded %s as watched despite a failed Add", path)
+	}
+
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	// This call must retry the Add now that the file exists.
+	w.setFiles([]string{path})
+	if _, ok := w.files[path]; !ok {
+		t.Fatalf("setFiles did not retry watching %s once it existed", path)
+	}
+
+	if err := os.WriteFile(path, []byte("y"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	waitForCount(t, &reloads, 1, 2*time.Second)
+}
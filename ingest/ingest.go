@@ -0,0 +1,95 @@
+// Copyright 2015 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ingest lets Alertmanager accept alerts in formats other than its
+// native JSON. Each Adapter translates a foreign payload into one or more
+// *types.Alert and is registered under its own /api/v1/ingest/<name> prefix,
+// so routing, inhibition, and silencing apply identically regardless of how
+// an alert arrived.
+package ingest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/common/log"
+	"github.com/prometheus/common/route"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// Adapter translates a source-specific payload into Alertmanager alerts.
+type Adapter interface {
+	// Name identifies the adapter and becomes its URL path segment under
+	// /api/v1/ingest.
+	Name() string
+	// Translate parses body into one or more alerts.
+	Translate(body []byte) ([]*types.Alert, error)
+}
+
+// Register mounts each adapter under r.WithPrefix("/" + adapter.Name()) and
+// funnels whatever it translates into alerts.Put, so the adapters share the
+// exact same ingestion path as the native API.
+func Register(r *route.Router, alerts provider.Alerts, adapters ...Adapter) {
+	for _, a := range adapters {
+		sub := r.WithPrefix("/" + a.Name())
+		sub.Post("/", handler(a, alerts))
+	}
+}
+
+// normalize applies the same defaulting the native /api/v1/alerts handler
+// does for alerts that don't set every field explicitly, so that an
+// ingested alert behaves identically to one submitted natively: without it,
+// an adapter that only fills in Labels/Annotations would leave StartsAt at
+// its zero value, which the dispatcher and UI would render as starting in
+// 1970 instead of now.
+func normalize(a *types.Alert) {
+	if a.StartsAt.IsZero() {
+		a.StartsAt = time.Now()
+	}
+}
+
+func handler(a Adapter, alerts provider.Alerts) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer req.Body.Close()
+
+		as, err := a.Translate(body)
+		if err != nil {
+			log.With("adapter", a.Name()).With("err", err).Errorln("Failed to translate ingested alert")
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, al := range as {
+			normalize(al)
+		}
+
+		if err := alerts.Put(as...); err != nil {
+			log.With("adapter", a.Name()).With("err", err).Errorln("Failed to store ingested alert")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(struct {
+			Status string `json:"status"`
+		}{"success"})
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2015 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestNormalizeDefaultsZeroStartsAt(t *testing.T) {
+	a := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "Test"}}}
+
+	before := time.Now()
+	normalize(a)
+	after := time.Now()
+
+	if a.StartsAt.Before(before) || a.StartsAt.After(after) {
+		t.Fatalf("normalize set StartsAt = %v, want a time between %v and %v", a.StartsAt, before, after)
+	}
+}
+
+func TestNormalizeLeavesExplicitStartsAt(t *testing.T) {
+	want := time.Now().Add(-time.Hour)
+	a := &types.Alert{Alert: model.Alert{StartsAt: want}}
+
+	normalize(a)
+
+	if !a.StartsAt.Equal(want) {
+		t.Fatalf("normalize overwrote an explicit StartsAt: got %v, want %v", a.StartsAt, want)
+	}
+}
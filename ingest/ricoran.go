@@ -0,0 +1,119 @@
+// Copyright 2015 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// ricOranAlarm is the subset of the O-RAN Alarm Notification schema this
+// adapter understands: a specific problem code, a perceived severity, and a
+// free-form bag of identifying information about the reporting network
+// function.
+type ricOranAlarm struct {
+	SpecificProblem   string            `json:"SpecificProblem"`
+	PerceivedSeverity string            `json:"PerceivedSeverity"`
+	IdentifyingInfo   map[string]string `json:"IdentifyingInfo"`
+}
+
+// severityLabels maps O-RAN PerceivedSeverity values onto the "severity"
+// label Alertmanager routing configs conventionally match on.
+var severityLabels = map[string]string{
+	"1": "critical", "CRITICAL": "critical",
+	"2": "major", "MAJOR": "major",
+	"3": "minor", "MINOR": "minor",
+	"4": "warning", "WARNING": "warning",
+	"0": "cleared", "CLEARED": "cleared",
+}
+
+// sanitizeLabelName rewrites s into a valid Prometheus label name by
+// replacing every character outside [a-zA-Z0-9_] with an underscore and
+// prefixing the result with an underscore if it would otherwise start with
+// a digit, so IdentifyingInfo keys can't make the whole alarm fail label
+// validation downstream.
+func sanitizeLabelName(s string) model.LabelName {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return model.LabelName(b.String())
+}
+
+// RICOranAdapter translates RIC/O-RAN alarm notifications into alerts,
+// mapping SpecificProblem/PerceivedSeverity/IdentifyingInfo onto labels and
+// annotations.
+type RICOranAdapter struct{}
+
+// NewRICOranAdapter returns a RIC/O-RAN alarm adapter.
+func NewRICOranAdapter() *RICOranAdapter { return &RICOranAdapter{} }
+
+// Name implements Adapter.
+func (RICOranAdapter) Name() string { return "ricoran" }
+
+// Translate implements Adapter.
+func (RICOranAdapter) Translate(body []byte) ([]*types.Alert, error) {
+	var alarm ricOranAlarm
+	if err := json.Unmarshal(body, &alarm); err != nil {
+		return nil, fmt.Errorf("ingest/ricoran: invalid alarm JSON: %v", err)
+	}
+	if alarm.SpecificProblem == "" {
+		return nil, fmt.Errorf("ingest/ricoran: alarm is missing SpecificProblem")
+	}
+
+	labels := model.LabelSet{
+		"alertname": model.LabelValue("ricoran_" + alarm.SpecificProblem),
+	}
+	if sev, ok := severityLabels[alarm.PerceivedSeverity]; ok {
+		labels["severity"] = model.LabelValue(sev)
+	} else if alarm.PerceivedSeverity != "" {
+		labels["severity"] = model.LabelValue(alarm.PerceivedSeverity)
+	}
+
+	annotations := model.LabelSet{}
+	for k, v := range alarm.IdentifyingInfo {
+		// IdentifyingInfo keys arrive as arbitrary, possibly non-label-safe
+		// strings from the network function; sanitize them since
+		// annotations are validated as label names too, and only promote
+		// well-known ones to labels below.
+		annotations[sanitizeLabelName(k)] = model.LabelValue(v)
+	}
+	if nf, ok := alarm.IdentifyingInfo["ManagedObjectId"]; ok {
+		labels["managed_object_id"] = model.LabelValue(nf)
+	}
+
+	return []*types.Alert{
+		{
+			Alert: model.Alert{
+				Labels:      labels,
+				Annotations: annotations,
+			},
+		},
+	}, nil
+}
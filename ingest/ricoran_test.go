@@ -0,0 +1,92 @@
+// Copyright 2015 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+)
+
+func TestRICOranAdapterTranslate(t *testing.T) {
+	body := []byte(`{
+		"SpecificProblem": "LinkDown",
+		"PerceivedSeverity": "CRITICAL",
+		"IdentifyingInfo": {"ManagedObjectId": "gnb-1"}
+	}`)
+
+	alerts, err := RICOranAdapter{}.Translate(body)
+	if err != nil {
+		t.Fatalf("Translate: %v", err)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(alerts))
+	}
+
+	a := alerts[0]
+	if got, want := a.Labels["alertname"], model.LabelValue("ricoran_LinkDown"); got != want {
+		t.Errorf("alertname label = %q, want %q", got, want)
+	}
+	if got, want := a.Labels["severity"], model.LabelValue("critical"); got != want {
+		t.Errorf("severity label = %q, want %q", got, want)
+	}
+	if got, want := a.Labels["managed_object_id"], model.LabelValue("gnb-1"); got != want {
+		t.Errorf("managed_object_id label = %q, want %q", got, want)
+	}
+}
+
+func TestRICOranAdapterTranslateRejectsMissingSpecificProblem(t *testing.T) {
+	_, err := RICOranAdapter{}.Translate([]byte(`{"PerceivedSeverity": "CRITICAL"}`))
+	if err == nil {
+		t.Fatal("Translate succeeded with no SpecificProblem, want an error")
+	}
+}
+
+func TestRICOranAdapterTranslateFallsBackToRawSeverity(t *testing.T) {
+	alerts, err := RICOranAdapter{}.Translate([]byte(`{
+		"SpecificProblem": "Flap",
+		"PerceivedSeverity": "vendor-custom-severity"
+	}`))
+	if err != nil {
+		t.Fatalf("Translate: %v", err)
+	}
+	if got, want := alerts[0].Labels["severity"], model.LabelValue("vendor-custom-severity"); got != want {
+		t.Errorf("severity label = %q, want %q", got, want)
+	}
+}
+
+func TestRICOranAdapterTranslateSanitizesIdentifyingInfoKeys(t *testing.T) {
+	body := []byte(`{
+		"SpecificProblem": "LinkDown",
+		"IdentifyingInfo": {"vendor specific-field!": "x", "1starts-with-digit": "y"}
+	}`)
+
+	alerts, err := RICOranAdapter{}.Translate(body)
+	if err != nil {
+		t.Fatalf("Translate: %v", err)
+	}
+
+	a := alerts[0]
+	if _, ok := a.Annotations["vendor_specific_field_"]; !ok {
+		t.Errorf("annotations = %v, want a sanitized key for %q", a.Annotations, "vendor specific-field!")
+	}
+	if _, ok := a.Annotations["_1starts_with_digit"]; !ok {
+		t.Errorf("annotations = %v, want a sanitized key for %q", a.Annotations, "1starts-with-digit")
+	}
+	for k := range a.Annotations {
+		if !model.LabelName(k).IsValid() {
+			t.Errorf("annotation key %q is not a valid label name", k)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2015 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// webhookMapping is the YAML document a user's "webhook.mapping" template is
+// expected to produce. StartsAt, EndsAt, and GeneratorURL mirror the
+// corresponding model.Alert fields and may be left unset; the ingest
+// dispatcher defaults StartsAt to now when it is zero.
+type webhookMapping struct {
+	Labels       model.LabelSet `yaml:"labels"`
+	Annotations  model.LabelSet `yaml:"annotations"`
+	StartsAt     time.Time      `yaml:"startsAt"`
+	EndsAt       time.Time      `yaml:"endsAt"`
+	GeneratorURL string         `yaml:"generatorURL"`
+}
+
+// WebhookAdapter maps arbitrary JSON payloads into alerts using a
+// user-supplied "webhook.mapping" template, loaded the same way notification
+// templates are via template.FromGlobs.
+type WebhookAdapter struct {
+	tmpl *template.Template
+}
+
+// NewWebhookAdapter loads the mapping template from the given globs.
+func NewWebhookAdapter(globs ...string) (*WebhookAdapter, error) {
+	tmpl, err := template.FromGlobs(globs...)
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookAdapter{tmpl: tmpl}, nil
+}
+
+// Name implements Adapter.
+func (wh *WebhookAdapter) Name() string { return "webhook" }
+
+// Translate implements Adapter. It decodes body as JSON, runs it through the
+// "webhook.mapping" template, and parses the result as a webhookMapping.
+func (wh *WebhookAdapter) Translate(body []byte) ([]*types.Alert, error) {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("ingest/webhook: invalid JSON payload: %v", err)
+	}
+
+	out, err := wh.tmpl.ExecuteTextString(`{{ template "webhook.mapping" . }}`, data)
+	if err != nil {
+		return nil, fmt.Errorf("ingest/webhook: executing mapping template: %v", err)
+	}
+
+	var mapping webhookMapping
+	if err := yaml.Unmarshal([]byte(out), &mapping); err != nil {
+		return nil, fmt.Errorf("ingest/webhook: mapping template did not produce valid YAML: %v", err)
+	}
+	if len(mapping.Labels) == 0 {
+		return nil, fmt.Errorf("ingest/webhook: mapping template produced no labels")
+	}
+
+	return []*types.Alert{
+		{
+			Alert: model.Alert{
+				Labels:       mapping.Labels,
+				Annotations:  mapping.Annotations,
+				StartsAt:     mapping.StartsAt,
+				EndsAt:       mapping.EndsAt,
+				GeneratorURL: mapping.GeneratorURL,
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2015 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testMappingTemplate = `{{ define "webhook.mapping" }}
+labels:
+  alertname: {{ .alert_type }}
+  severity: {{ .severity }}
+annotations:
+  summary: {{ .message }}
+{{ end }}`
+
+func newTestWebhookAdapter(t *testing.T) *WebhookAdapter {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "webhook.tmpl")
+	if err := os.WriteFile(path, []byte(testMappingTemplate), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	wh, err := NewWebhookAdapter(path)
+	if err != nil {
+		t.Fatalf("NewWebhookAdapter: %v", err)
+	}
+	return wh
+}
+
+func TestWebhookAdapterTranslate(t *testing.T) {
+	wh := newTestWebhookAdapter(t)
+
+	body := []byte(`{"alert_type": "disk_full", "severity": "critical", "message": "disk is full"}`)
+	alerts, err := wh.Translate(body)
+	if err != nil {
+		t.Fatalf("Translate: %v", err)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(alerts))
+	}
+
+	a := alerts[0]
+	if got, want := string(a.Labels["alertname"]), "disk_full"; got != want {
+		t.Errorf("alertname label = %q, want %q", got, want)
+	}
+	if got, want := string(a.Annotations["summary"]), "disk is full"; got != want {
+		t.Errorf("summary annotation = %q, want %q", got, want)
+	}
+	if !a.StartsAt.IsZero() {
+		t.Errorf("StartsAt = %v, want zero value since the mapping didn't set one (normalize fills it in later)", a.StartsAt)
+	}
+}
+
+func TestWebhookAdapterTranslateRejectsInvalidJSON(t *testing.T) {
+	wh := newTestWebhookAdapter(t)
+	if _, err := wh.Translate([]byte(`not json`)); err == nil {
+		t.Fatal("Translate succeeded on invalid JSON, want an error")
+	}
+}
+
+func TestWebhookAdapterTranslateRejectsEmptyLabels(t *testing.T) {
+	// With testMappingTemplate, a payload missing alert_type/severity/message
+	// still renders a labels map (its values become "<no value>"), so use a
+	// mapping that genuinely produces none.
+	path := filepath.Join(t.TempDir(), "webhook.tmpl")
+	if err := os.WriteFile(path, []byte(`{{ define "webhook.mapping" }}annotations:
+  summary: nothing
+{{ end }}`), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	wh2, err := NewWebhookAdapter(path)
+	if err != nil {
+		t.Fatalf("NewWebhookAdapter: %v", err)
+	}
+
+	if _, err := wh2.Translate([]byte(`{}`)); err == nil {
+		t.Fatal("Translate succeeded with no labels, want an error")
+	}
+}
+
+func TestWebhookAdapterTranslatePassesThroughStartsAt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhook.tmpl")
+	tmpl := `{{ define "webhook.mapping" }}
+labels:
+  alertname: {{ .alert_type }}
+startsAt: {{ .starts_at }}
+{{ end }}`
+	if err := os.WriteFile(path, []byte(tmpl), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	wh, err := NewWebhookAdapter(path)
+	if err != nil {
+		t.Fatalf("NewWebhookAdapter: %v", err)
+	}
+
+	want := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	body := []byte(`{"alert_type": "disk_full", "starts_at": "` + want.Format(time.RFC3339) + `"}`)
+	alerts, err := wh.Translate(body)
+	if err != nil {
+		t.Fatalf("Translate: %v", err)
+	}
+	if !alerts[0].StartsAt.Equal(want) {
+		t.Errorf("StartsAt = %v, want %v", alerts[0].StartsAt, want)
+	}
+}
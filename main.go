@@ -14,59 +14,118 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"flag"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/prometheus/common/log"
 	"github.com/prometheus/common/route"
 
 	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/ingest"
 	"github.com/prometheus/alertmanager/notify"
 	"github.com/prometheus/alertmanager/provider"
 	"github.com/prometheus/alertmanager/template"
 )
 
 var (
-	configFile    = flag.String("config.file", "config.yml", "The configuration file")
-	dataDir       = flag.String("data.dir", "data/", "The data directory")
-	listenAddress = flag.String("web.listen-address", ":9093", "Address to listen on for the web interface and API.")
+	configFile      = flag.String("config.file", "config.yml", "The configuration file")
+	dataDir         = flag.String("data.dir", "data/", "The data directory")
+	listenAddress   = flag.String("web.listen-address", ":9093", "Address to listen on for the web interface and API.")
+	shutdownTimeout = flag.Duration("web.shutdown-timeout", 20*time.Second, "Maximum time to wait for in-flight connections and notifications to drain during a graceful shutdown.")
+	webTimeout      = flag.Duration("web.timeout", 30*time.Second, "Read and write timeout for the web API.")
+
+	webTLSCertFile     = flag.String("web.tls-cert-file", "", "Certificate file for HTTPS; setting this and --web.tls-key-file enables TLS.")
+	webTLSKeyFile      = flag.String("web.tls-key-file", "", "Private key file for HTTPS.")
+	webTLSClientCAFile = flag.String("web.tls-client-ca-file", "", "CA certificate used to verify client certificates; setting this requires and enforces mutual TLS on the web API, including the silence-mutating endpoints.")
+
+	configAutoReload = flag.Bool("config.auto-reload", false, "Watch the configuration file and its templates for changes and reload automatically, in addition to reloading on SIGHUP.")
+
+	storageBackend     = flag.String("storage.backend", "ql", "The storage backend to use for alerts, silences, and notification state. One of: ql, boltdb, mem.")
+	storageMigrateFrom = flag.String("storage.migrate-from", "", "One-shot: migrate alert and silence state from this backend (currently only \"ql\" is supported) into the boltdb database under --data.dir, then exit. Run this once before switching --storage.backend to boltdb.")
+
+	notifyQueueCapacity = flag.Int("notify.queue-capacity", 1000, "Per-shard buffer size of the notification queue.")
+	notifyShards        = flag.Int("notify.shards", 4, "Number of per-receiver notification queue shards.")
+	notifyMaxBatchSize  = flag.Int("notify.max-batch-size", 64, "Maximum number of alerts sent to a receiver in a single notification.")
+
+	ingestWebhookTemplates = flag.String("ingest.webhook-template-glob", "", "Glob matching the text/template file(s) defining the \"webhook.mapping\" template used by the generic webhook ingest adapter.")
 )
 
-func main() {
-	flag.Parse()
+// tlsConfig builds the *tls.Config used by the web server from the
+// --web.tls-* flags, or returns nil if TLS was not configured.
+func tlsConfig() (*tls.Config, error) {
+	if *webTLSCertFile == "" && *webTLSKeyFile == "" {
+		if *webTLSClientCAFile != "" {
+			return nil, errors.New("--web.tls-client-ca-file requires --web.tls-cert-file/--web.tls-key-file")
+		}
+		return nil, nil
+	}
 
-	db, err := sql.Open("ql", filepath.Join(*dataDir, "am.db"))
-	if err != nil {
-		log.Fatal(err)
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
 	}
-	defer db.Close()
 
-	alerts, err := provider.NewSQLAlerts(db)
-	if err != nil {
-		log.Fatal(err)
+	if *webTLSClientCAFile != "" {
+		ca, err := ioutil.ReadFile(*webTLSClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("failed to parse client CA certificate")
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
 	}
-	notifies, err := provider.NewSQLNotifyInfo(db)
-	if err != nil {
-		log.Fatal(err)
+
+	return cfg, nil
+}
+
+func main() {
+	flag.Parse()
+
+	if *storageMigrateFrom != "" {
+		if err := provider.Migrate(provider.Backend(*storageMigrateFrom), *dataDir); err != nil {
+			log.Fatal(err)
+		}
+		log.Infoln("Migration complete; restart with --storage.backend=boltdb")
+		return
 	}
-	silences, err := provider.NewSQLSilences(db)
+
+	stores, err := provider.NewStores(provider.Backend(*storageBackend), *dataDir)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer stores.Close()
+
+	var (
+		alerts   = stores.Alerts
+		notifies = stores.NotifyInfo
+		silences = stores.Silences
+	)
 
 	var (
 		inhibitor *Inhibitor
 		tmpl      *template.Template
 		disp      *Dispatcher
+		queues    []*notify.Queue
 	)
-	defer disp.Stop()
 
 	build := func(nconf []*config.NotificationConfig) notify.Notifier {
+		for _, q := range queues {
+			q.Stop(context.Background())
+		}
+		queues = queues[:0]
+
 		var (
 			router  = notify.Router{}
 			fanouts = notify.Build(nconf, tmpl)
@@ -78,6 +137,10 @@ func main() {
 				n = notify.Dedup(notifies, n)
 				n = notify.Log(n, log.With("step", "dedup"))
 
+				q := notify.NewQueue(name, n, *notifyShards, *notifyQueueCapacity, *notifyMaxBatchSize)
+				queues = append(queues, q)
+				n = q
+
 				fo[i] = n
 			}
 			router[name] = fo
@@ -93,7 +156,18 @@ func main() {
 		return n
 	}
 
-	reload := func() (err error) {
+	var watcher *configWatcher
+
+	// reloadMtx serializes reload, which mutates disp, tmpl, inhibitor, and
+	// queues below: it now has two callers, the SIGHUP handler and the
+	// config watcher's goroutine, and without this they could race on that
+	// shared state if a signal and a file change land close together.
+	var reloadMtx sync.Mutex
+
+	reload := instrumentedReload(func() (err error) {
+		reloadMtx.Lock()
+		defer reloadMtx.Unlock()
+
 		log.With("file", *configFile).Infof("Loading configuration file")
 		defer func() {
 			if err != nil {
@@ -111,6 +185,10 @@ func main() {
 			return err
 		}
 
+		if watcher != nil {
+			watcher.setFiles(append([]string{*configFile}, templateFiles(conf.Templates)...))
+		}
+
 		disp.Stop()
 
 		inhibitor = NewInhibitor(alerts, conf.InhibitRules)
@@ -119,20 +197,71 @@ func main() {
 		go disp.Run()
 
 		return nil
+	}, func() float64 { return float64(time.Now().Unix()) })
+
+	if *configAutoReload {
+		watcher, err = newConfigWatcher(reload)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer watcher.Close()
+		watcher.setFiles([]string{*configFile})
 	}
 
 	if err := reload(); err != nil {
 		os.Exit(1)
 	}
 
+	tlsCfg, err := tlsConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Client-cert enforcement happens once, at the listener: when
+	// --web.tls-client-ca-file is set, tlsCfg.ClientAuth above is
+	// RequireAndVerifyClientCert and tlsCfg becomes srv.TLSConfig below, so every request on every route
+	// (including the /api/v1/silences mutating endpoints) already requires
+	// a verified client certificate. There is no per-route knob to wire
+	// into NewAPI here.
 	router := route.New()
 	NewAPI(router.WithPrefix("/api/v1"), alerts, silences)
 
-	go http.ListenAndServe(*listenAddress, router)
+	ingestAdapters := []ingest.Adapter{ingest.NewRICOranAdapter()}
+	if *ingestWebhookTemplates != "" {
+		wh, err := ingest.NewWebhookAdapter(*ingestWebhookTemplates)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ingestAdapters = append(ingestAdapters, wh)
+	}
+	ingest.Register(router.WithPrefix("/api/v1/ingest"), alerts, ingestAdapters...)
+
+	srv := &http.Server{
+		Addr:         *listenAddress,
+		Handler:      router,
+		TLSConfig:    tlsCfg,
+		ReadTimeout:  *webTimeout,
+		WriteTimeout: *webTimeout,
+	}
+
+	srvErr := make(chan error, 1)
+	go func() {
+		var err error
+		if tlsCfg != nil {
+			err = srv.ListenAndServeTLS(*webTLSCertFile, *webTLSKeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			srvErr <- err
+			return
+		}
+		close(srvErr)
+	}()
 
 	var (
-		hup  = make(chan os.Signal)
-		term = make(chan os.Signal)
+		hup  = make(chan os.Signal, 1)
+		term = make(chan os.Signal, 1)
 	)
 	signal.Notify(hup, syscall.SIGHUP)
 	signal.Notify(term, os.Interrupt, syscall.SIGTERM)
@@ -143,7 +272,39 @@ func main() {
 		}
 	}()
 
-	<-term
+	select {
+	case <-term:
+		log.Infoln("Received SIGTERM, exiting gracefully...")
+	case err := <-srvErr:
+		log.With("err", err).Errorln("Web server exited unexpectedly, shutting down...")
+	}
+
+	// Stop accepting new HTTP connections first, then stop the dispatcher,
+	// then drain the notification queues, whose workers run the
+	// retry/dedup pipeline, and only then close the underlying storage providers (via the deferred
+	// stores.Close above). This mirrors the run-group teardown order used
+	// in Prometheus' main: listener, then subsystem, then storage. ctx
+	// bounds the whole sequence, not just srv.Shutdown, so a stuck
+	// receiver can't hang shutdown past --web.shutdown-timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
 
-	log.Infoln("Received SIGTERM, exiting gracefully...")
+	if err := srv.Shutdown(ctx); err != nil {
+		log.With("err", err).Errorln("Error shutting down web server")
+	}
+
+	dispStopped := make(chan struct{})
+	go func() {
+		disp.Stop()
+		close(dispStopped)
+	}()
+	select {
+	case <-dispStopped:
+	case <-ctx.Done():
+		log.Warnln("Shutdown timeout exceeded waiting for dispatcher to stop")
+	}
+
+	for _, q := range queues {
+		q.Stop(ctx)
+	}
 }
@@ -0,0 +1,129 @@
+// Copyright 2015 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// withTLSFlags sets the --web.tls-* flag values for the duration of a test
+// and restores their previous values on cleanup.
+func withTLSFlags(t *testing.T, cert, key, clientCA string) {
+	t.Helper()
+	prevCert, prevKey, prevCA := *webTLSCertFile, *webTLSKeyFile, *webTLSClientCAFile
+	*webTLSCertFile, *webTLSKeyFile, *webTLSClientCAFile = cert, key, clientCA
+	t.Cleanup(func() {
+		*webTLSCertFile, *webTLSKeyFile, *webTLSClientCAFile = prevCert, prevKey, prevCA
+	})
+}
+
+// writeTestCA writes a self-signed CA certificate to dir and returns its
+// path, for tests that need tlsConfig to actually parse one.
+func writeTestCA(t *testing.T, dir string) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+
+	path := filepath.Join(dir, "ca.pem")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer f.Close()
+	if err := pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		t.Fatalf("pem.Encode: %v", err)
+	}
+	return path
+}
+
+func TestTLSConfigDisabledWhenNoFlagsSet(t *testing.T) {
+	withTLSFlags(t, "", "", "")
+
+	cfg, err := tlsConfig()
+	if err != nil {
+		t.Fatalf("tlsConfig: %v", err)
+	}
+	if cfg != nil {
+		t.Fatalf("tlsConfig = %+v, want nil", cfg)
+	}
+}
+
+func TestTLSConfigRejectsClientCAWithoutCertAndKey(t *testing.T) {
+	dir := t.TempDir()
+	withTLSFlags(t, "", "", writeTestCA(t, dir))
+
+	if _, err := tlsConfig(); err == nil {
+		t.Fatal("tlsConfig succeeded with --web.tls-client-ca-file but no cert/key, want an error")
+	}
+}
+
+func TestTLSConfigWithClientCAEnforcesClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	withTLSFlags(t, "cert.pem", "key.pem", writeTestCA(t, dir))
+
+	cfg, err := tlsConfig()
+	if err != nil {
+		t.Fatalf("tlsConfig: %v", err)
+	}
+	if cfg == nil {
+		t.Fatal("tlsConfig = nil, want a non-nil *tls.Config")
+	}
+	if cfg.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("ClientAuth = %v, want RequireAndVerifyClientCert", cfg.ClientAuth)
+	}
+	if cfg.ClientCAs == nil {
+		t.Error("ClientCAs = nil, want the parsed CA pool")
+	}
+}
+
+func TestTLSConfigWithoutClientCADoesNotRequireClientCert(t *testing.T) {
+	withTLSFlags(t, "cert.pem", "key.pem", "")
+
+	cfg, err := tlsConfig()
+	if err != nil {
+		t.Fatalf("tlsConfig: %v", err)
+	}
+	if cfg == nil {
+		t.Fatal("tlsConfig = nil, want a non-nil *tls.Config")
+	}
+	if cfg.ClientAuth == tls.RequireAndVerifyClientCert {
+		t.Error("ClientAuth = RequireAndVerifyClientCert without --web.tls-client-ca-file set")
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2015 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// queueFlushDeadline bounds how long a partially filled batch waits for more
+// alerts before being sent anyway, so a quiet receiver doesn't hold alerts
+// indefinitely while its batch stays below maxBatch.
+const queueFlushDeadline = 1 * time.Second
+
+var (
+	queueLength = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "alertmanager_notification_queue_length",
+		Help: "The number of alerts currently waiting in the notification queue.",
+	}, []string{"receiver"})
+	queueCapacity = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "alertmanager_notification_queue_capacity",
+		Help: "The total capacity of the notification queue.",
+	}, []string{"receiver"})
+	notificationsDroppedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "alertmanager_notifications_dropped_total",
+		Help: "Total number of alerts dropped because the notification queue was full.",
+	}, []string{"receiver"})
+	notificationLatencySeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "alertmanager_notification_latency_seconds",
+		Help: "Latency of sending a batch of notifications to a receiver.",
+	}, []string{"receiver"})
+)
+
+func init() {
+	prometheus.MustRegister(queueLength)
+	prometheus.MustRegister(queueCapacity)
+	prometheus.MustRegister(notificationsDroppedTotal)
+	prometheus.MustRegister(notificationLatencySeconds)
+}
+
+// Queue sits in front of a Notifier and fans alerts for a single receiver
+// out across a fixed number of shards, selected by alert fingerprint, so
+// that a slow receiver only back-pressures the alerts hashed to its own
+// shards rather than every alert routed through the same fanout. Each shard
+// batches up to maxBatch alerts before calling through to next, or flushes
+// early after queueFlushDeadline so low-traffic shards still make progress.
+type Queue struct {
+	receiver string
+	next     Notifier
+
+	shards   []chan *types.Alert
+	capacity int
+	wg       sync.WaitGroup
+}
+
+// NewQueue returns a Queue for receiver that enqueues onto shards channels
+// of capacity each, batching up to maxBatch alerts per call to next.Notify.
+func NewQueue(receiver string, next Notifier, shards, capacity, maxBatch int) *Queue {
+	if shards < 1 {
+		shards = 1
+	}
+	if maxBatch < 1 {
+		maxBatch = 1
+	}
+
+	q := &Queue{
+		receiver: receiver,
+		next:     next,
+		shards:   make([]chan *types.Alert, shards),
+		capacity: shards * capacity,
+	}
+
+	// A receiver can have more than one integration, each getting its own
+	// Queue; Add rather than Set so the gauge reports the sum across all of
+	// them instead of the last one created clobbering the rest.
+	queueCapacity.WithLabelValues(receiver).Add(float64(q.capacity))
+
+	for i := range q.shards {
+		ch := make(chan *types.Alert, capacity)
+		q.shards[i] = ch
+
+		q.wg.Add(1)
+		go q.drain(ch, maxBatch)
+	}
+
+	return q
+}
+
+// Notify enqueues alerts onto their shards and returns immediately; the
+// actual send happens on the shard's drain goroutine. An alert is dropped,
+// and counted in alertmanager_notifications_dropped_total, if its shard's
+// queue is full.
+func (q *Queue) Notify(alerts ...*types.Alert) error {
+	for _, a := range alerts {
+		ch := q.shards[q.shardFor(a)]
+		select {
+		case ch <- a:
+			queueLength.WithLabelValues(q.receiver).Inc()
+		default:
+			notificationsDroppedTotal.WithLabelValues(q.receiver).Inc()
+			log.With("receiver", q.receiver).With("alert", a.Fingerprint()).Warnln("Notification queue full, dropping alert")
+		}
+	}
+	return nil
+}
+
+func (q *Queue) shardFor(a *types.Alert) int {
+	h := fnv.New32a()
+	h.Write([]byte(a.Fingerprint().String()))
+	return int(h.Sum32() % uint32(len(q.shards)))
+}
+
+func (q *Queue) drain(ch chan *types.Alert, maxBatch int) {
+	defer q.wg.Done()
+
+	batch := make([]*types.Alert, 0, maxBatch)
+	timer := time.NewTimer(queueFlushDeadline)
+	defer timer.Stop()
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		queueLength.WithLabelValues(q.receiver).Sub(float64(len(batch)))
+
+		start := time.Now()
+		if err := q.next.Notify(batch...); err != nil {
+			log.With("receiver", q.receiver).With("err", err).Errorln("Error sending queued notifications")
+		}
+		notificationLatencySeconds.WithLabelValues(q.receiver).Observe(time.Since(start).Seconds())
+
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case a, ok := <-ch:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, a)
+			if len(batch) >= maxBatch {
+				flush()
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(queueFlushDeadline)
+			}
+
+		case <-timer.C:
+			flush()
+			timer.Reset(queueFlushDeadline)
+		}
+	}
+}
+
+// Stop closes every shard and waits for its pending batch to be flushed
+// through to next, up to ctx's deadline, then removes this Queue's
+// contribution from the capacity gauge. If ctx is done first (a receiver's
+// Notify, retry included, is taking too long), Stop returns without waiting
+// further for the drain goroutines, which keep running in the background.
+func (q *Queue) Stop(ctx context.Context) {
+	for _, ch := range q.shards {
+		close(ch)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.With("receiver", q.receiver).Warnln("Shutdown deadline exceeded waiting for queued notifications to drain")
+	}
+
+	queueCapacity.WithLabelValues(q.receiver).Sub(float64(q.capacity))
+}
@@ -0,0 +1,179 @@
+// Copyright 2015 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// notifierFunc adapts a function to the Notifier interface for tests.
+type notifierFunc func(alerts ...*types.Alert) error
+
+func (f notifierFunc) Notify(alerts ...*types.Alert) error { return f(alerts...) }
+
+// recordingNotifier collects every batch passed to Notify.
+type recordingNotifier struct {
+	mtx     sync.Mutex
+	batches [][]*types.Alert
+}
+
+func (r *recordingNotifier) Notify(alerts ...*types.Alert) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.batches = append(r.batches, alerts)
+	return nil
+}
+
+func (r *recordingNotifier) count() int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	n := 0
+	for _, b := range r.batches {
+		n += len(b)
+	}
+	return n
+}
+
+func testAlert(name string) *types.Alert {
+	return &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": model.LabelValue(name)}}}
+}
+
+func TestQueueFlushesOnStop(t *testing.T) {
+	rec := &recordingNotifier{}
+	q := NewQueue("test", rec, 2, 10, 64)
+
+	for i := 0; i < 5; i++ {
+		if err := q.Notify(testAlert("a")); err != nil {
+			t.Fatalf("Notify: %v", err)
+		}
+	}
+
+	// Stop must drain whatever is still sitting in the shards rather than
+	// dropping it, since this is what graceful shutdown relies on.
+	q.Stop(context.Background())
+
+	if got := rec.count(); got != 5 {
+		t.Fatalf("after Stop, notifier received %d alerts, want 5", got)
+	}
+}
+
+func TestQueueFlushesOnDeadlineWithoutFillingBatch(t *testing.T) {
+	rec := &recordingNotifier{}
+	q := NewQueue("test", rec, 1, 10, 64)
+	defer q.Stop(context.Background())
+
+	if err := q.Notify(testAlert("a")); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * queueFlushDeadline)
+	for rec.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := rec.count(); got != 1 {
+		t.Fatalf("notifier received %d alerts before the flush deadline fired, want 1", got)
+	}
+}
+
+func TestQueueDropsWhenShardFull(t *testing.T) {
+	block := make(chan struct{})
+	blocking := notifierFunc(func(alerts ...*types.Alert) error {
+		<-block
+		return nil
+	})
+	defer close(block)
+
+	q := NewQueue("test", blocking, 1, 1, 1)
+	defer q.Stop(context.Background())
+
+	// The first alert is picked up by the drain goroutine immediately and
+	// blocks it; the shard's one-slot buffer absorbs a second; a third must
+	// be dropped rather than block Notify.
+	for i := 0; i < 2; i++ {
+		if err := q.Notify(testAlert("a")); err != nil {
+			t.Fatalf("Notify: %v", err)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		q.Notify(testAlert("a"))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Notify blocked instead of dropping the alert for a full shard")
+	}
+}
+
+func TestQueueStopRespectsContextDeadline(t *testing.T) {
+	block := make(chan struct{})
+	blocking := notifierFunc(func(alerts ...*types.Alert) error {
+		<-block
+		return nil
+	})
+	defer close(block)
+
+	q := NewQueue("test", blocking, 1, 1, 1)
+	if err := q.Notify(testAlert("a")); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		q.Stop(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Stop blocked past its context deadline waiting on a stuck notifier")
+	}
+}
+
+func TestQueueCapacityGaugeAccumulatesAcrossQueues(t *testing.T) {
+	gauge := queueCapacity.WithLabelValues("accum-test")
+	before := testutil.ToFloat64(gauge)
+
+	q1 := NewQueue("accum-test", &recordingNotifier{}, 2, 5, 1)
+	q2 := NewQueue("accum-test", &recordingNotifier{}, 3, 5, 1)
+
+	if got := testutil.ToFloat64(gauge) - before; got != 25 {
+		t.Fatalf("queue capacity gauge = %v after two queues, want %v", got, 25)
+	}
+
+	q1.Stop(context.Background())
+	if got := testutil.ToFloat64(gauge) - before; got != 15 {
+		t.Fatalf("queue capacity gauge = %v after stopping one queue, want %v", got, 15)
+	}
+	q2.Stop(context.Background())
+	if got := testutil.ToFloat64(gauge) - before; got != 0 {
+		t.Fatalf("queue capacity gauge = %v after stopping both queues, want 0", got)
+	}
+}
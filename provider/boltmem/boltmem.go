@@ -0,0 +1,327 @@
+// Copyright 2015 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package boltmem implements the provider.Alerts, provider.NotifyInfo, and
+// provider.Silences interfaces on top of a single boltdb database, keyed by
+// alert fingerprint and silence ID. Silence lookups by matcher name
+// (SilencesByMatcher) are backed by a secondary index bucket rather than a
+// full scan of the silences bucket; no other method reads that index.
+package boltmem
+
+import (
+	"encoding/json"
+
+	"github.com/boltdb/bolt"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+var (
+	bucketAlerts            = []byte("alerts")
+	bucketSilences          = []byte("silences")
+	bucketSilencesByMatcher = []byte("silences-by-matcher")
+	bucketNotifyInfo        = []byte("notify-info")
+)
+
+// matcherIndexKey builds the bucketSilencesByMatcher key for a given matcher
+// name and silence ID: the name, a NUL separator, then the ID, so a prefix
+// scan on "name\x00" finds every silence matching on that label name.
+func matcherIndexKey(name, id string) []byte {
+	return append(append([]byte(name), 0), []byte(id)...)
+}
+
+func hasPrefix(b, prefix []byte) bool {
+	if len(b) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if b[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Alerts is a boltdb-backed provider.Alerts implementation, keyed by
+// fingerprint.
+//
+// Alerts do not carry a "receiver" label (that's a routing-tree decision
+// made downstream, not a property of the alert), so there is no sound
+// secondary index to build here; GetPending scans the alerts bucket, which
+// is the same access pattern the ql-backed provider uses.
+type Alerts struct {
+	db *bolt.DB
+}
+
+// NewAlerts returns a new boltdb-backed Alerts provider, creating its
+// bucket if it does not already exist.
+func NewAlerts(db *bolt.DB) (*Alerts, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketAlerts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Alerts{db: db}, nil
+}
+
+// Put stores the given alerts.
+func (a *Alerts) Put(alerts ...*types.Alert) error {
+	return a.db.Update(func(tx *bolt.Tx) error {
+		ab := tx.Bucket(bucketAlerts)
+
+		for _, al := range alerts {
+			buf, err := json.Marshal(al)
+			if err != nil {
+				return err
+			}
+			fp := al.Fingerprint()
+			if err := ab.Put([]byte(fp.String()), buf); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get returns the alert for the given fingerprint.
+func (a *Alerts) Get(fp model.Fingerprint) (*types.Alert, error) {
+	var al types.Alert
+	err := a.db.View(func(tx *bolt.Tx) error {
+		buf := tx.Bucket(bucketAlerts).Get([]byte(fp.String()))
+		if buf == nil {
+			return provider.ErrNotFound
+		}
+		return json.Unmarshal(buf, &al)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &al, nil
+}
+
+// GetPending returns an iterator over all currently stored alerts.
+func (a *Alerts) GetPending() (provider.AlertIterator, error) {
+	var alerts []*types.Alert
+	err := a.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketAlerts).ForEach(func(_, v []byte) error {
+			al := &types.Alert{}
+			if err := json.Unmarshal(v, al); err != nil {
+				return err
+			}
+			alerts = append(alerts, al)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return provider.NewAlertIterator(alerts), nil
+}
+
+// Silences is a boltdb-backed provider.Silences implementation, indexed by
+// ID with a secondary index over matcher names so SilencesByMatcher doesn't
+// require scanning every stored silence. Silence and Silences read the
+// silences bucket directly.
+type Silences struct {
+	db *bolt.DB
+}
+
+// NewSilences returns a new boltdb-backed Silences provider, creating its
+// buckets if they do not already exist.
+func NewSilences(db *bolt.DB) (*Silences, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{bucketSilences, bucketSilencesByMatcher} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Silences{db: db}, nil
+}
+
+// SetSilence stores sil and indexes it by the names of its matchers.
+func (s *Silences) SetSilence(sil *types.Silence) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		sb := tx.Bucket(bucketSilences)
+		mb := tx.Bucket(bucketSilencesByMatcher)
+
+		buf, err := json.Marshal(sil)
+		if err != nil {
+			return err
+		}
+		if err := sb.Put([]byte(sil.ID), buf); err != nil {
+			return err
+		}
+		for _, m := range sil.Matchers {
+			if err := mb.Put(matcherIndexKey(m.Name, sil.ID), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DelSilence removes the silence with the given ID, along with its entries
+// in the matcher index.
+func (s *Silences) DelSilence(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		sb := tx.Bucket(bucketSilences)
+		mb := tx.Bucket(bucketSilencesByMatcher)
+
+		buf := sb.Get([]byte(id))
+		if buf == nil {
+			return provider.ErrNotFound
+		}
+		var sil types.Silence
+		if err := json.Unmarshal(buf, &sil); err != nil {
+			return err
+		}
+		for _, m := range sil.Matchers {
+			if err := mb.Delete(matcherIndexKey(m.Name, id)); err != nil {
+				return err
+			}
+		}
+		return sb.Delete([]byte(id))
+	})
+}
+
+// SilencesByMatcher returns every stored silence that has a matcher on the
+// given label name, using the matcher index instead of scanning every
+// stored silence.
+func (s *Silences) SilencesByMatcher(name string) ([]*types.Silence, error) {
+	var out []*types.Silence
+	err := s.db.View(func(tx *bolt.Tx) error {
+		sb := tx.Bucket(bucketSilences)
+		c := tx.Bucket(bucketSilencesByMatcher).Cursor()
+
+		prefix := append([]byte(name), 0)
+		for k, _ := c.Seek(prefix); k != nil && hasPrefix(k, prefix); k, _ = c.Next() {
+			id := k[len(prefix):]
+			buf := sb.Get(id)
+			if buf == nil {
+				// Index entry outlived its silence; ignore rather than fail
+				// the whole lookup.
+				continue
+			}
+			sil := &types.Silence{}
+			if err := json.Unmarshal(buf, sil); err != nil {
+				return err
+			}
+			out = append(out, sil)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// Silence returns the silence with the given ID.
+func (s *Silences) Silence(id string) (*types.Silence, error) {
+	var sil types.Silence
+	err := s.db.View(func(tx *bolt.Tx) error {
+		buf := tx.Bucket(bucketSilences).Get([]byte(id))
+		if buf == nil {
+			return provider.ErrNotFound
+		}
+		return json.Unmarshal(buf, &sil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sil, nil
+}
+
+// Silences returns all stored silences.
+func (s *Silences) Silences() ([]*types.Silence, error) {
+	var out []*types.Silence
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSilences).ForEach(func(_, v []byte) error {
+			sil := &types.Silence{}
+			if err := json.Unmarshal(v, sil); err != nil {
+				return err
+			}
+			out = append(out, sil)
+			return nil
+		})
+	})
+	return out, err
+}
+
+// NotifyInfo is a boltdb-backed provider.NotifyInfo implementation, keyed by
+// the combination of receiver name and alert fingerprint.
+type NotifyInfo struct {
+	db *bolt.DB
+}
+
+// NewNotifyInfo returns a new boltdb-backed NotifyInfo provider, creating
+// its bucket if it does not already exist.
+func NewNotifyInfo(db *bolt.DB) (*NotifyInfo, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketNotifyInfo)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &NotifyInfo{db: db}, nil
+}
+
+func notifyKey(name string, fp model.Fingerprint) []byte {
+	return []byte(name + "\x00" + fp.String())
+}
+
+// Set stores the notify state for the given receiver/fingerprint pairs.
+func (n *NotifyInfo) Set(ni ...*types.NotifyInfo) error {
+	return n.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketNotifyInfo)
+		for _, info := range ni {
+			buf, err := json.Marshal(info)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(notifyKey(info.Receiver, info.Alert), buf); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get returns the stored notify state for the given receiver/fingerprint
+// pairs, with a nil entry where none is stored.
+func (n *NotifyInfo) Get(dest string, fps ...model.Fingerprint) ([]*types.NotifyInfo, error) {
+	out := make([]*types.NotifyInfo, len(fps))
+	err := n.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketNotifyInfo)
+		for i, fp := range fps {
+			buf := b.Get(notifyKey(dest, fp))
+			if buf == nil {
+				continue
+			}
+			info := &types.NotifyInfo{}
+			if err := json.Unmarshal(buf, info); err != nil {
+				return err
+			}
+			out[i] = info
+		}
+		return nil
+	})
+	return out, err
+}
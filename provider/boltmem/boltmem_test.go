@@ -0,0 +1,144 @@
+// Copyright 2015 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltmem
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func openTestDB(t *testing.T) *bolt.DB {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("open bolt db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newSilence(id, matcherName string) *types.Silence {
+	return &types.Silence{
+		ID: id,
+		Matchers: []*types.Matcher{
+			{Name: matcherName, Value: "x"},
+		},
+	}
+}
+
+func TestSilencesByMatcherFindsIndexedSilences(t *testing.T) {
+	s, err := NewSilences(openTestDB(t))
+	if err != nil {
+		t.Fatalf("NewSilences: %v", err)
+	}
+
+	if err := s.SetSilence(newSilence("a", "alertname")); err != nil {
+		t.Fatalf("SetSilence: %v", err)
+	}
+	if err := s.SetSilence(newSilence("b", "alertname")); err != nil {
+		t.Fatalf("SetSilence: %v", err)
+	}
+	if err := s.SetSilence(newSilence("c", "severity")); err != nil {
+		t.Fatalf("SetSilence: %v", err)
+	}
+
+	got, err := s.SilencesByMatcher("alertname")
+	if err != nil {
+		t.Fatalf("SilencesByMatcher: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("SilencesByMatcher(\"alertname\") = %d silences, want 2", len(got))
+	}
+
+	ids := map[string]bool{}
+	for _, sil := range got {
+		ids[sil.ID] = true
+	}
+	if !ids["a"] || !ids["b"] {
+		t.Fatalf("SilencesByMatcher(\"alertname\") = %v, want {a, b}", ids)
+	}
+}
+
+func TestSilencesByMatcherDoesNotMatchOnPrefix(t *testing.T) {
+	s, err := NewSilences(openTestDB(t))
+	if err != nil {
+		t.Fatalf("NewSilences: %v", err)
+	}
+
+	if err := s.SetSilence(newSilence("a", "alert")); err != nil {
+		t.Fatalf("SetSilence: %v", err)
+	}
+	if err := s.SetSilence(newSilence("b", "alertname")); err != nil {
+		t.Fatalf("SetSilence: %v", err)
+	}
+
+	got, err := s.SilencesByMatcher("alert")
+	if err != nil {
+		t.Fatalf("SilencesByMatcher: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "a" {
+		t.Fatalf("SilencesByMatcher(\"alert\") = %v, want only silence \"a\"", got)
+	}
+}
+
+func TestDelSilenceRemovesMatcherIndexEntries(t *testing.T) {
+	s, err := NewSilences(openTestDB(t))
+	if err != nil {
+		t.Fatalf("NewSilences: %v", err)
+	}
+
+	if err := s.SetSilence(newSilence("a", "alertname")); err != nil {
+		t.Fatalf("SetSilence: %v", err)
+	}
+	if err := s.DelSilence("a"); err != nil {
+		t.Fatalf("DelSilence: %v", err)
+	}
+
+	got, err := s.SilencesByMatcher("alertname")
+	if err != nil {
+		t.Fatalf("SilencesByMatcher: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("SilencesByMatcher(\"alertname\") after delete = %v, want none; matcher index was left orphaned", got)
+	}
+}
+
+func TestAlertsPutAndGet(t *testing.T) {
+	a, err := NewAlerts(openTestDB(t))
+	if err != nil {
+		t.Fatalf("NewAlerts: %v", err)
+	}
+
+	al := &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{"alertname": "Test"},
+		},
+	}
+	if err := a.Put(al); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	got, err := a.Get(al.Fingerprint())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Labels["alertname"] != "Test" {
+		t.Fatalf("Get returned %v, want alertname=Test", got.Labels)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2015 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/prometheus/alertmanager/provider/boltmem"
+)
+
+// Backend identifies one of the storage implementations the factory in this
+// package knows how to build.
+type Backend string
+
+const (
+	BackendQL     Backend = "ql"
+	BackendBoltDB Backend = "boltdb"
+	BackendMemory Backend = "mem"
+)
+
+// Stores bundles the three provider interfaces Alertmanager needs, plus a
+// Close to release whatever the backend opened underneath them.
+type Stores struct {
+	Alerts     Alerts
+	NotifyInfo NotifyInfo
+	Silences   Silences
+	Close      func() error
+}
+
+// NewStores opens the storage backend named by backend, rooted at dataDir,
+// and returns the Alerts/NotifyInfo/Silences implementations for it.
+func NewStores(backend Backend, dataDir string) (*Stores, error) {
+	switch backend {
+	case BackendQL, "":
+		db, err := sql.Open("ql", filepath.Join(dataDir, "am.db"))
+		if err != nil {
+			return nil, err
+		}
+		alerts, err := NewSQLAlerts(db)
+		if err != nil {
+			return nil, err
+		}
+		notifies, err := NewSQLNotifyInfo(db)
+		if err != nil {
+			return nil, err
+		}
+		silences, err := NewSQLSilences(db)
+		if err != nil {
+			return nil, err
+		}
+		return &Stores{Alerts: alerts, NotifyInfo: notifies, Silences: silences, Close: db.Close}, nil
+
+	case BackendBoltDB:
+		db, err := bolt.Open(filepath.Join(dataDir, "am.bolt.db"), 0666, nil)
+		if err != nil {
+			return nil, err
+		}
+		alerts, err := boltmem.NewAlerts(db)
+		if err != nil {
+			return nil, err
+		}
+		notifies, err := boltmem.NewNotifyInfo(db)
+		if err != nil {
+			return nil, err
+		}
+		silences, err := boltmem.NewSilences(db)
+		if err != nil {
+			return nil, err
+		}
+		return &Stores{Alerts: alerts, NotifyInfo: notifies, Silences: silences, Close: db.Close}, nil
+
+	case BackendMemory:
+		alerts, err := NewMemAlerts()
+		if err != nil {
+			return nil, err
+		}
+		notifies, err := NewMemNotifyInfo()
+		if err != nil {
+			return nil, err
+		}
+		silences, err := NewMemSilences()
+		if err != nil {
+			return nil, err
+		}
+		return &Stores{Alerts: alerts, NotifyInfo: notifies, Silences: silences, Close: func() error { return nil }}, nil
+	}
+
+	return nil, fmt.Errorf("provider: unknown storage backend %q", backend)
+}
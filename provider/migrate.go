@@ -0,0 +1,98 @@
+// Copyright 2015 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/prometheus/alertmanager/provider/boltmem"
+)
+
+// MigrateQLToBoltDB reads all alerts and silences out of a ql database and
+// writes them into a boltdb database, so an existing install can
+// switch --storage.backend from "ql" to "boltdb" without losing state.
+// Notification dedup state is intentionally not carried over: it is a cache
+// that rebuilds itself from the next round of notifications.
+func MigrateQLToBoltDB(qlDB *sql.DB, boltDB *bolt.DB) error {
+	srcAlerts, err := NewSQLAlerts(qlDB)
+	if err != nil {
+		return fmt.Errorf("open source alerts: %v", err)
+	}
+	srcSilences, err := NewSQLSilences(qlDB)
+	if err != nil {
+		return fmt.Errorf("open source silences: %v", err)
+	}
+
+	dstAlerts, err := boltmem.NewAlerts(boltDB)
+	if err != nil {
+		return fmt.Errorf("open destination alerts: %v", err)
+	}
+	dstSilences, err := boltmem.NewSilences(boltDB)
+	if err != nil {
+		return fmt.Errorf("open destination silences: %v", err)
+	}
+
+	it, err := srcAlerts.GetPending()
+	if err != nil {
+		return fmt.Errorf("read source alerts: %v", err)
+	}
+	for al := range it.Next() {
+		if err := dstAlerts.Put(al); err != nil {
+			return fmt.Errorf("write alert %v: %v", al.Fingerprint(), err)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return fmt.Errorf("read source alerts: %v", err)
+	}
+
+	sils, err := srcSilences.Silences()
+	if err != nil {
+		return fmt.Errorf("read source silences: %v", err)
+	}
+	for _, sil := range sils {
+		if err := dstSilences.SetSilence(sil); err != nil {
+			return fmt.Errorf("write silence %s: %v", sil.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Migrate opens the ql database and the boltdb database rooted at dataDir
+// and runs MigrateQLToBoltDB between them, closing both before returning.
+// It is the one-shot operation behind --storage.migrate-from=ql: run it,
+// then restart Alertmanager with --storage.backend=boltdb.
+func Migrate(from Backend, dataDir string) error {
+	if from != BackendQL {
+		return fmt.Errorf("provider: migration from backend %q is not supported, only %q", from, BackendQL)
+	}
+
+	qlDB, err := sql.Open("ql", filepath.Join(dataDir, "am.db"))
+	if err != nil {
+		return fmt.Errorf("open ql database: %v", err)
+	}
+	defer qlDB.Close()
+
+	boltDB, err := bolt.Open(filepath.Join(dataDir, "am.bolt.db"), 0666, nil)
+	if err != nil {
+		return fmt.Errorf("open boltdb database: %v", err)
+	}
+	defer boltDB.Close()
+
+	return MigrateQLToBoltDB(qlDB, boltDB)
+}